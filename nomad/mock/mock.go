@@ -0,0 +1,77 @@
+// Package mock provides constructors for structs fixtures, used by tests
+// across the server, client and scheduler packages.
+package mock
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+var uuidCounter uint64
+
+// GenerateUUID returns a unique identifier suitable for IDs in test
+// fixtures. It isn't a real UUID; it just never repeats within a process.
+func GenerateUUID() string {
+	n := atomic.AddUint64(&uuidCounter, 1)
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", n)
+}
+
+// Node returns a ready node with a generic resource profile.
+func Node() *structs.Node {
+	return &structs.Node{
+		ID:         GenerateUUID(),
+		Datacenter: "dc1",
+		Class:      "linux-64bit",
+		Status:     structs.NodeStatusReady,
+		Drivers: map[string]bool{
+			"exec": true,
+		},
+		Resources: &structs.Resources{
+			CPU:      4000,
+			MemoryMB: 8192,
+			DiskMB:   100 * 1024,
+		},
+	}
+}
+
+// Job returns a single task group service job.
+func Job() *structs.Job {
+	return &structs.Job{
+		ID:          GenerateUUID(),
+		Priority:    50,
+		Datacenters: []string{"dc1"},
+		TaskGroups: []*structs.TaskGroup{
+			{
+				Name:  "web",
+				Count: 1,
+				Tasks: []*structs.Task{
+					{
+						Name:   "web",
+						Driver: "exec",
+						Resources: &structs.Resources{
+							CPU:      500,
+							MemoryMB: 256,
+							DiskMB:   512,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Alloc returns a running allocation fixture.
+func Alloc() *structs.Allocation {
+	return &structs.Allocation{
+		ID:     GenerateUUID(),
+		Name:   "web.0",
+		Status: structs.AllocStatusRunning,
+		Resources: &structs.Resources{
+			CPU:      500,
+			MemoryMB: 256,
+			DiskMB:   512,
+		},
+	}
+}
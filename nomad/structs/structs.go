@@ -0,0 +1,381 @@
+// Package structs contains the data types shared between the server,
+// client and scheduler.
+package structs
+
+import "time"
+
+// Resources describes a quantity of CPU, memory and disk.
+type Resources struct {
+	CPU      int
+	MemoryMB int
+	DiskMB   int
+}
+
+// Add accumulates other's resources into r.
+func (r *Resources) Add(other *Resources) {
+	if other == nil {
+		return
+	}
+	r.CPU += other.CPU
+	r.MemoryMB += other.MemoryMB
+	r.DiskMB += other.DiskMB
+}
+
+// Subtract removes other's resources from r.
+func (r *Resources) Subtract(other *Resources) {
+	if other == nil {
+		return
+	}
+	r.CPU -= other.CPU
+	r.MemoryMB -= other.MemoryMB
+	r.DiskMB -= other.DiskMB
+}
+
+// Constraint restricts placement based on a node attribute. It is
+// comparable with == so task lists can be diffed cheaply.
+type Constraint struct {
+	LTarget string
+	RTarget string
+	Operand string
+}
+
+// String renders the constraint the way operators write it in a job spec,
+// used when reporting why a node was filtered.
+func (c *Constraint) String() string {
+	return c.LTarget + " " + c.Operand + " " + c.RTarget
+}
+
+// Task is a single unit of work within a TaskGroup.
+type Task struct {
+	Name        string
+	Driver      string
+	Constraints []*Constraint
+	Resources   *Resources
+}
+
+// UpdateStrategy controls how a task group's allocations are rolled out
+// when its definition changes in a way that requires a destructive update.
+type UpdateStrategy struct {
+	// MaxParallel is the number of allocations that can be updated at the
+	// same time.
+	MaxParallel int
+
+	// MinHealthyTime is how long an updated allocation must report
+	// Running before the next batch is allowed to start.
+	MinHealthyTime time.Duration
+
+	// Stagger is the minimum delay between launching successive batches
+	// of a rolling update.
+	Stagger time.Duration
+}
+
+// TaskGroup is a set of tasks that are co-located on a node and scaled
+// together.
+type TaskGroup struct {
+	Name        string
+	Count       int
+	Constraints []*Constraint
+	Tasks       []*Task
+	Update      *UpdateStrategy
+}
+
+// Job is the user-specified definition of a set of task groups.
+type Job struct {
+	ID          string
+	Priority    int
+	Datacenters []string
+	Constraints []*Constraint
+	TaskGroups  []*TaskGroup
+
+	// ModifyIndex changes every time the job definition is updated. It's
+	// used to tell whether an allocation is running the current version
+	// of its job.
+	ModifyIndex uint64
+}
+
+// LookupTaskGroup returns the task group with the given name, or nil.
+func (j *Job) LookupTaskGroup(name string) *TaskGroup {
+	for _, tg := range j.TaskGroups {
+		if tg.Name == name {
+			return tg
+		}
+	}
+	return nil
+}
+
+// Allocation statuses.
+const (
+	AllocStatusPending = "pending"
+	AllocStatusRunning = "running"
+	AllocStatusFailed  = "failed"
+	AllocStatusLost    = "lost"
+)
+
+// Allocation is a placement of a task group's instance onto a node.
+type Allocation struct {
+	ID         string
+	Name       string
+	NodeID     string
+	JobID      string
+	Job        *Job
+	TaskGroup  string
+	Resources  *Resources
+	Metrics    *AllocMetric
+	Status     string
+	ModifyTime time.Time
+}
+
+// Node statuses.
+const (
+	NodeStatusReady = "ready"
+	NodeStatusDown  = "down"
+)
+
+// Node is a machine capable of running allocations.
+type Node struct {
+	ID         string
+	Datacenter string
+	Class      string
+	Status     string
+
+	// Drain marks a node that should have its allocations migrated off
+	// even though it may still be Ready.
+	Drain bool
+
+	// Drivers lists the task drivers this node can run.
+	Drivers   map[string]bool
+	Resources *Resources
+}
+
+// AllocMetric is a set of counters recorded while the scheduler evaluates
+// nodes for a placement, used to explain why an allocation did or didn't
+// place.
+type AllocMetric struct {
+	NodesEvaluated int
+	NodesFiltered  int
+
+	// ClassFiltered counts nodes filtered for lacking a required task
+	// driver, keyed by driver name.
+	ClassFiltered map[string]int
+
+	// ConstraintFiltered counts nodes filtered by a constraint, keyed by
+	// the constraint's text.
+	ConstraintFiltered map[string]int
+
+	NodesExhausted int
+
+	// ClassExhausted counts nodes whose resources were exhausted, keyed
+	// by node class.
+	ClassExhausted map[string]int
+
+	// DimensionExhausted counts nodes that didn't have enough of a given
+	// resource dimension (cpu/memory/disk).
+	DimensionExhausted map[string]int
+
+	// NodesAvailable counts the nodes considered for placement, keyed by
+	// datacenter.
+	NodesAvailable map[string]int
+
+	// Scores records the score assigned to each candidate node
+	// considered by the ranking iterators, keyed by node ID.
+	Scores map[string]float64
+
+	AllocationTime    time.Duration
+	CoalescedFailures int
+}
+
+// Reset clears the metric back to its zero value so it can be reused for
+// the next placement attempt.
+func (a *AllocMetric) Reset() {
+	*a = AllocMetric{}
+}
+
+// EvaluatedNode records that a node was considered for placement.
+func (a *AllocMetric) EvaluatedNode() {
+	a.NodesEvaluated++
+}
+
+// FilterDriver records that a node was filtered for lacking a task driver.
+func (a *AllocMetric) FilterDriver(driver string) {
+	a.NodesFiltered++
+	if a.ClassFiltered == nil {
+		a.ClassFiltered = make(map[string]int)
+	}
+	a.ClassFiltered[driver]++
+}
+
+// FilterConstraint records that a node was filtered by a constraint,
+// identified by its text so operators can see which expression failed.
+func (a *AllocMetric) FilterConstraint(constraint string) {
+	a.NodesFiltered++
+	if a.ConstraintFiltered == nil {
+		a.ConstraintFiltered = make(map[string]int)
+	}
+	a.ConstraintFiltered[constraint]++
+}
+
+// ExhaustDimension records that a node didn't have enough of the given
+// resource dimension to bin pack the allocation.
+func (a *AllocMetric) ExhaustDimension(dimension string) {
+	a.NodesExhausted++
+	if a.DimensionExhausted == nil {
+		a.DimensionExhausted = make(map[string]int)
+	}
+	a.DimensionExhausted[dimension]++
+}
+
+// ScoreNode records the score the ranking iterators assigned to a
+// candidate node.
+func (a *AllocMetric) ScoreNode(node *Node, score float64) {
+	if a.Scores == nil {
+		a.Scores = make(map[string]float64)
+	}
+	a.Scores[node.ID] = score
+}
+
+// SetNodesAvailable records how many nodes were available for placement,
+// keyed by datacenter, for the placement-diagnostics report.
+func (a *AllocMetric) SetNodesAvailable(counts map[string]int) {
+	a.NodesAvailable = make(map[string]int, len(counts))
+	for k, v := range counts {
+		a.NodesAvailable[k] = v
+	}
+}
+
+// Plan is the set of changes a scheduler proposes in response to an
+// evaluation.
+type Plan struct {
+	EvalID   string
+	Priority int
+
+	// NodeEvict maps a node ID to the allocation IDs evicted from it.
+	NodeEvict map[string][]string
+
+	// NodeAllocation maps a node ID to the allocations newly placed on
+	// it.
+	NodeAllocation map[string][]*Allocation
+
+	// NodeUpdate maps a node ID to allocations updated in place on it.
+	NodeUpdate map[string][]*Allocation
+
+	// NodePreempt maps a node ID to allocations evicted to make room for
+	// a higher priority placement.
+	NodePreempt map[string][]*Allocation
+
+	// FailedAllocs maps an allocation name that could not be placed to
+	// the metrics collected while trying to place it.
+	FailedAllocs map[string]*AllocMetric
+}
+
+// AppendEvict marks an allocation to be evicted from its node.
+func (p *Plan) AppendEvict(alloc *Allocation) {
+	if p.NodeEvict == nil {
+		p.NodeEvict = make(map[string][]string)
+	}
+	p.NodeEvict[alloc.NodeID] = append(p.NodeEvict[alloc.NodeID], alloc.ID)
+}
+
+// AppendAlloc adds a newly placed allocation to the plan.
+func (p *Plan) AppendAlloc(alloc *Allocation) {
+	if p.NodeAllocation == nil {
+		p.NodeAllocation = make(map[string][]*Allocation)
+	}
+	p.NodeAllocation[alloc.NodeID] = append(p.NodeAllocation[alloc.NodeID], alloc)
+}
+
+// AppendUpdate adds an in-place update to the plan.
+func (p *Plan) AppendUpdate(alloc *Allocation) {
+	if p.NodeUpdate == nil {
+		p.NodeUpdate = make(map[string][]*Allocation)
+	}
+	p.NodeUpdate[alloc.NodeID] = append(p.NodeUpdate[alloc.NodeID], alloc)
+}
+
+// AppendPreemption marks an allocation to be evicted to make room for a
+// higher priority placement.
+func (p *Plan) AppendPreemption(alloc *Allocation) {
+	if p.NodePreempt == nil {
+		p.NodePreempt = make(map[string][]*Allocation)
+	}
+	p.NodePreempt[alloc.NodeID] = append(p.NodePreempt[alloc.NodeID], alloc)
+}
+
+// AppendBlocked records the metrics collected while failing to place name
+// so operators can see why it didn't place.
+func (p *Plan) AppendBlocked(name string, metric *AllocMetric) {
+	if p.FailedAllocs == nil {
+		p.FailedAllocs = make(map[string]*AllocMetric)
+	}
+	p.FailedAllocs[name] = metric
+}
+
+// PlanResult is returned by the planner once a Plan has been applied,
+// reflecting what was actually committed (another scheduler may have raced
+// for the same nodes).
+type PlanResult struct {
+	NodeUpdate     map[string][]*Allocation
+	NodeAllocation map[string][]*Allocation
+}
+
+// FullCommit reports whether every allocation in plan was actually
+// committed, along with the expected and actual placement counts.
+func (p *PlanResult) FullCommit(plan *Plan) (bool, int, int) {
+	expected := 0
+	for _, allocs := range plan.NodeAllocation {
+		expected += len(allocs)
+	}
+	actual := 0
+	for _, allocs := range p.NodeAllocation {
+		actual += len(allocs)
+	}
+	return actual >= expected, expected, actual
+}
+
+// Evaluation trigger reasons.
+const (
+	EvalTriggerJobRegister   = "job-register"
+	EvalTriggerJobDeregister = "job-deregister"
+	EvalTriggerNodeUpdate    = "node-update"
+)
+
+// Evaluation is work queued for a scheduler to process.
+type Evaluation struct {
+	ID          string
+	Priority    int
+	JobID       string
+	NodeID      string
+	TriggeredBy string
+
+	// Wait is how long the planner should delay dispatching this
+	// evaluation, used to space out a staggered rolling update.
+	Wait time.Duration
+}
+
+// MakePlan starts a new, empty plan for this evaluation.
+func (e *Evaluation) MakePlan(job *Job) *Plan {
+	return &Plan{
+		EvalID:    e.ID,
+		Priority:  e.Priority,
+		NodeEvict: make(map[string][]string),
+	}
+}
+
+// NextRollingEval returns a follow-up evaluation used to continue a
+// rolling update that didn't finish in this pass.
+func (e *Evaluation) NextRollingEval() *Evaluation {
+	return &Evaluation{
+		ID:          e.ID + "-rolling",
+		Priority:    e.Priority,
+		JobID:       e.JobID,
+		TriggeredBy: EvalTriggerJobRegister,
+	}
+}
+
+// SchedulerConfiguration holds cluster-wide scheduler tuning set by
+// operators.
+type SchedulerConfiguration struct {
+	// MinPreemptionPriorityGap is the minimum difference in priority
+	// required before a placement is allowed to preempt an existing
+	// allocation.
+	MinPreemptionPriorityGap int
+}
@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// BinPackIterator scores each feasible node by how tightly the proposed
+// allocation would pack onto it, preferring nodes that leave the least
+// free capacity behind. When eviction is enabled and a node doesn't have
+// enough free capacity on its own, it looks for a set of lower priority
+// allocations whose removal would make the placement fit, and proposes
+// preempting them instead of rejecting the node outright.
+type BinPackIterator struct {
+	ctx         *EvalContext
+	source      Iterator
+	resources   *structs.Resources
+	evict       bool
+	priority    int
+	priorityGap int
+}
+
+// NewBinPackIterator creates a BinPackIterator over source. priorityGap is
+// the minimum difference in priority required before a placement is
+// allowed to preempt an allocation already on a node.
+func NewBinPackIterator(ctx *EvalContext, source Iterator, resources *structs.Resources,
+	evict bool, priority int, priorityGap int) *BinPackIterator {
+	return &BinPackIterator{
+		ctx:         ctx,
+		source:      source,
+		resources:   resources,
+		evict:       evict,
+		priority:    priority,
+		priorityGap: priorityGap,
+	}
+}
+
+// SetResources replaces the resource footprint being packed, used when the
+// stack is reused across task groups within the same evaluation.
+func (it *BinPackIterator) SetResources(r *structs.Resources) {
+	it.resources = r
+}
+
+func (it *BinPackIterator) Next() interface{} {
+	for {
+		raw := it.source.Next()
+		if raw == nil {
+			return nil
+		}
+		ranked := raw.(*RankedNode)
+		node := ranked.Node
+
+		allocs, err := it.ctx.State().AllocsByNode(node.ID)
+		if err != nil {
+			continue
+		}
+		free := freeResources(node, allocs)
+
+		if fitsResources(it.resources, free) {
+			ranked.Score = packingScore(it.resources, free)
+			return ranked
+		}
+
+		dim := exhaustedDimension(it.resources, free)
+		if !it.evict {
+			it.ctx.Metrics().ExhaustDimension(dim)
+			continue
+		}
+
+		preempted, ok := it.preemptionSet(allocs, free)
+		if !ok {
+			it.ctx.Metrics().ExhaustDimension(dim)
+			continue
+		}
+
+		ranked.PreemptedAllocs = preempted
+		ranked.Score = packingScore(it.resources, free)
+		return ranked
+	}
+}
+
+func (it *BinPackIterator) Reset() { it.source.Reset() }
+
+// preemptionSet takes a node's allocations whose job priority trails the
+// pending placement by at least priorityGap, in ascending priority order,
+// and stops at the first of them whose combined removal frees enough
+// capacity for it to fit. The result is the lowest-priority prefix that
+// fits, not necessarily the smallest possible set. It returns ok=false if
+// even all of them together don't free enough, e.g. because every
+// allocation on the node is within the priority gap.
+func (it *BinPackIterator) preemptionSet(allocs []*structs.Allocation, free *structs.Resources) ([]*structs.Allocation, bool) {
+	candidates := make([]*structs.Allocation, 0, len(allocs))
+	for _, a := range allocs {
+		if a.Job == nil {
+			continue
+		}
+		if it.priority-a.Job.Priority < it.priorityGap {
+			continue
+		}
+		candidates = append(candidates, a)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Job.Priority < candidates[j].Job.Priority
+	})
+
+	available := new(structs.Resources)
+	available.Add(free)
+
+	var preempted []*structs.Allocation
+	for _, a := range candidates {
+		if fitsResources(it.resources, available) {
+			break
+		}
+		available.Add(a.Resources)
+		preempted = append(preempted, a)
+	}
+
+	if !fitsResources(it.resources, available) {
+		return nil, false
+	}
+	return preempted, true
+}
+
+// freeResources returns a node's unallocated capacity by subtracting the
+// footprint of every allocation already placed on it from its total
+// capacity.
+func freeResources(node *structs.Node, allocs []*structs.Allocation) *structs.Resources {
+	free := new(structs.Resources)
+	free.Add(node.Resources)
+	for _, a := range allocs {
+		free.Subtract(a.Resources)
+	}
+	return free
+}
+
+// fitsResources reports whether need fits within free along every
+// dimension.
+func fitsResources(need, free *structs.Resources) bool {
+	if need == nil {
+		return true
+	}
+	return need.CPU <= free.CPU && need.MemoryMB <= free.MemoryMB && need.DiskMB <= free.DiskMB
+}
+
+// exhaustedDimension returns which resource dimension caused need not to
+// fit within free, for the DimensionExhausted placement diagnostics.
+func exhaustedDimension(need, free *structs.Resources) string {
+	switch {
+	case need.CPU > free.CPU:
+		return "cpu"
+	case need.MemoryMB > free.MemoryMB:
+		return "memory"
+	default:
+		return "disk"
+	}
+}
+
+// packingScore favors nodes that would be left with the least free CPU
+// capacity, so allocations are packed tightly rather than spread thin
+// across the cluster.
+func packingScore(need, free *structs.Resources) float64 {
+	if free.CPU <= 0 {
+		return 0
+	}
+	return float64(need.CPU) / float64(free.CPU)
+}
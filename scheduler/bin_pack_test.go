@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestBinPackIterator_Preempts_LowerPriority(t *testing.T) {
+	h := NewHarness(t)
+
+	node := mock.Node()
+	node.Resources = &structs.Resources{CPU: 1000, MemoryMB: 1000, DiskMB: 1000}
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	lowPrio := mock.Job()
+	lowPrio.Priority = 10
+	noErr(t, h.State.UpsertJob(h.NextIndex(), lowPrio))
+
+	existing := mock.Alloc()
+	existing.Job = lowPrio
+	existing.JobID = lowPrio.ID
+	existing.NodeID = node.ID
+	existing.Resources = &structs.Resources{CPU: 900, MemoryMB: 900, DiskMB: 900}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{existing}))
+
+	ctx := NewEvalContext(h.State, new(structs.Plan), nil)
+	source := NewStaticIterator(ctx, []*structs.Node{node})
+	rank := NewFeasibleRankIterator(ctx, source)
+
+	need := &structs.Resources{CPU: 500, MemoryMB: 500, DiskMB: 500}
+	bp := NewBinPackIterator(ctx, rank, need, true, 50, 10)
+
+	raw := bp.Next()
+	if raw == nil {
+		t.Fatalf("expected a candidate despite insufficient free capacity")
+	}
+	ranked := raw.(*RankedNode)
+	if len(ranked.PreemptedAllocs) != 1 || ranked.PreemptedAllocs[0].ID != existing.ID {
+		t.Fatalf("expected the lower priority alloc to be preempted, got %v", ranked.PreemptedAllocs)
+	}
+}
+
+func TestBinPackIterator_NoPreempt_WithinPriorityGap(t *testing.T) {
+	h := NewHarness(t)
+
+	node := mock.Node()
+	node.Resources = &structs.Resources{CPU: 1000, MemoryMB: 1000, DiskMB: 1000}
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	samePrio := mock.Job()
+	samePrio.Priority = 45
+	noErr(t, h.State.UpsertJob(h.NextIndex(), samePrio))
+
+	existing := mock.Alloc()
+	existing.Job = samePrio
+	existing.JobID = samePrio.ID
+	existing.NodeID = node.ID
+	existing.Resources = &structs.Resources{CPU: 900, MemoryMB: 900, DiskMB: 900}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{existing}))
+
+	ctx := NewEvalContext(h.State, new(structs.Plan), nil)
+	source := NewStaticIterator(ctx, []*structs.Node{node})
+	rank := NewFeasibleRankIterator(ctx, source)
+
+	need := &structs.Resources{CPU: 500, MemoryMB: 500, DiskMB: 500}
+	bp := NewBinPackIterator(ctx, rank, need, true, 50, 10)
+
+	if raw := bp.Next(); raw != nil {
+		t.Fatalf("expected no candidate when the only alloc is within the priority gap, got %v", raw)
+	}
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import "github.com/hashicorp/nomad/nomad/structs"
+
+// ConstraintIterator filters out nodes that don't satisfy a set of
+// constraints. It's used twice in the iterator stack: once for the job's
+// constraints and once for the currently-considered task group's.
+type ConstraintIterator struct {
+	ctx         *EvalContext
+	source      Iterator
+	constraints []*structs.Constraint
+}
+
+// NewConstraintIterator creates a ConstraintIterator over source.
+func NewConstraintIterator(ctx *EvalContext, source Iterator, constraints []*structs.Constraint) *ConstraintIterator {
+	return &ConstraintIterator{ctx: ctx, source: source, constraints: constraints}
+}
+
+// SetConstraints replaces the constraints being filtered on, used when the
+// stack is reused across task groups within the same evaluation.
+func (it *ConstraintIterator) SetConstraints(constraints []*structs.Constraint) {
+	it.constraints = constraints
+}
+
+func (it *ConstraintIterator) Next() interface{} {
+	for {
+		raw := it.source.Next()
+		if raw == nil {
+			return nil
+		}
+		node := raw.(*structs.Node)
+		it.ctx.Metrics().EvaluatedNode()
+
+		if failed := firstFailedConstraint(node, it.constraints); failed != nil {
+			it.ctx.Metrics().FilterConstraint(failed.String())
+			continue
+		}
+		return node
+	}
+}
+
+func (it *ConstraintIterator) Reset() { it.source.Reset() }
+
+// firstFailedConstraint returns the first constraint a node doesn't
+// satisfy, or nil if it satisfies all of them.
+func firstFailedConstraint(node *structs.Node, constraints []*structs.Constraint) *structs.Constraint {
+	for _, c := range constraints {
+		if !meetsConstraint(node, c) {
+			return c
+		}
+	}
+	return nil
+}
+
+// meetsConstraint evaluates a single constraint against a node's
+// attributes.
+func meetsConstraint(node *structs.Node, c *structs.Constraint) bool {
+	var left string
+	switch c.LTarget {
+	case "${node.class}":
+		left = node.Class
+	case "${node.datacenter}":
+		left = node.Datacenter
+	default:
+		// Attributes this minimal node model doesn't track are treated
+		// as satisfied rather than rejecting every node outright.
+		return true
+	}
+
+	switch c.Operand {
+	case "=", "==":
+		return left == c.RTarget
+	case "!=":
+		return left != c.RTarget
+	default:
+		return true
+	}
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"log"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// EvalContext is threaded through the iterator stack for a single
+// evaluation. It carries the state used for lookups, the plan being built,
+// and the metrics object the feasibility and ranking iterators record
+// their filtering decisions into.
+type EvalContext struct {
+	state   State
+	plan    *structs.Plan
+	logger  *log.Logger
+	metrics *structs.AllocMetric
+}
+
+// NewEvalContext creates the context shared by every iterator in a single
+// evaluation's stack.
+func NewEvalContext(state State, plan *structs.Plan, logger *log.Logger) *EvalContext {
+	return &EvalContext{
+		state:   state,
+		plan:    plan,
+		logger:  logger,
+		metrics: new(structs.AllocMetric),
+	}
+}
+
+// State returns the state used for node and allocation lookups.
+func (c *EvalContext) State() State { return c.state }
+
+// SetState replaces the state, used after a plan submission returns a
+// refreshed snapshot.
+func (c *EvalContext) SetState(s State) { c.state = s }
+
+// Plan returns the plan being built for this evaluation.
+func (c *EvalContext) Plan() *structs.Plan { return c.plan }
+
+// Logger returns the shared scheduler logger.
+func (c *EvalContext) Logger() *log.Logger { return c.logger }
+
+// Metrics returns the shared AllocMetric that the iterator stack records
+// into for the task group currently being placed. Callers must snapshot a
+// copy before it is reset for the next placement attempt.
+func (c *EvalContext) Metrics() *structs.AllocMetric { return c.metrics }
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestCopyAllocMetric_DoesNotAliasMaps(t *testing.T) {
+	m := &structs.AllocMetric{
+		ClassFiltered:      map[string]int{"exec": 1},
+		ConstraintFiltered: map[string]int{"${node.class} = x": 1},
+		ClassExhausted:     map[string]int{"linux-64bit": 1},
+		DimensionExhausted: map[string]int{"cpu": 1},
+		NodesAvailable:     map[string]int{"dc1": 3},
+		Scores:             map[string]float64{"node1": 0.5},
+	}
+
+	copied := copyAllocMetric(m)
+
+	// Resetting the source, as happens between placement attempts, must
+	// not affect the copy stashed on an earlier allocation or blocked
+	// report.
+	m.Reset()
+
+	if copied.NodesAvailable["dc1"] != 3 {
+		t.Fatalf("expected copied NodesAvailable to survive a Reset of the source, got %v", copied.NodesAvailable)
+	}
+	if copied.ClassFiltered["exec"] != 1 || copied.ConstraintFiltered["${node.class} = x"] != 1 ||
+		copied.ClassExhausted["linux-64bit"] != 1 || copied.DimensionExhausted["cpu"] != 1 ||
+		copied.Scores["node1"] != 0.5 {
+		t.Fatalf("expected every map field to survive a Reset of the source, got %+v", copied)
+	}
+}
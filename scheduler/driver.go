@@ -0,0 +1,51 @@
+package scheduler
+
+import "github.com/hashicorp/nomad/nomad/structs"
+
+// DriverIterator filters out nodes that can't run every driver required by
+// a task group's tasks.
+type DriverIterator struct {
+	ctx     *EvalContext
+	source  Iterator
+	drivers map[string]struct{}
+}
+
+// NewDriverIterator creates a DriverIterator over source.
+func NewDriverIterator(ctx *EvalContext, source Iterator, drivers map[string]struct{}) *DriverIterator {
+	return &DriverIterator{ctx: ctx, source: source, drivers: drivers}
+}
+
+// SetDrivers replaces the set of drivers being filtered on, used when the
+// stack is reused across task groups within the same evaluation.
+func (it *DriverIterator) SetDrivers(drivers map[string]struct{}) {
+	it.drivers = drivers
+}
+
+func (it *DriverIterator) Next() interface{} {
+	for {
+		raw := it.source.Next()
+		if raw == nil {
+			return nil
+		}
+		node := raw.(*structs.Node)
+
+		if missing := it.missingDriver(node); missing != "" {
+			it.ctx.Metrics().FilterDriver(missing)
+			continue
+		}
+		return node
+	}
+}
+
+func (it *DriverIterator) Reset() { it.source.Reset() }
+
+// missingDriver returns the name of the first required driver the node
+// doesn't support, or "" if it supports them all.
+func (it *DriverIterator) missingDriver(node *structs.Node) string {
+	for driver := range it.drivers {
+		if !node.Drivers[driver] {
+			return driver
+		}
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package scheduler
+
+import (
+	"log"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func noErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+// MemState is an in-memory State implementation backing the test Harness.
+type MemState struct {
+	nodes  map[string]*structs.Node
+	jobs   map[string]*structs.Job
+	allocs map[string]*structs.Allocation
+}
+
+func newMemState() *MemState {
+	return &MemState{
+		nodes:  make(map[string]*structs.Node),
+		jobs:   make(map[string]*structs.Job),
+		allocs: make(map[string]*structs.Allocation),
+	}
+}
+
+func (s *MemState) UpsertNode(index uint64, node *structs.Node) error {
+	s.nodes[node.ID] = node
+	return nil
+}
+
+func (s *MemState) UpsertJob(index uint64, job *structs.Job) error {
+	job.ModifyIndex = index
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemState) UpsertAllocs(index uint64, allocs []*structs.Allocation) error {
+	for _, a := range allocs {
+		s.allocs[a.ID] = a
+	}
+	return nil
+}
+
+func (s *MemState) GetJobByID(jobID string) (*structs.Job, error) {
+	return s.jobs[jobID], nil
+}
+
+func (s *MemState) GetNodeByID(nodeID string) (*structs.Node, error) {
+	return s.nodes[nodeID], nil
+}
+
+// sliceIterator adapts a slice of nodes gathered up front to the
+// StateIterator interface expected by the scheduler's lookups.
+type sliceIterator struct {
+	nodes []*structs.Node
+	idx   int
+}
+
+func (it *sliceIterator) Next() interface{} {
+	if it.idx >= len(it.nodes) {
+		return nil
+	}
+	n := it.nodes[it.idx]
+	it.idx++
+	return n
+}
+
+func (s *MemState) NodesByDatacenterStatus(dc, status string) (StateIterator, error) {
+	var out []*structs.Node
+	for _, n := range s.nodes {
+		if n.Datacenter == dc && n.Status == status {
+			out = append(out, n)
+		}
+	}
+	return &sliceIterator{nodes: out}, nil
+}
+
+func (s *MemState) AllocsByJob(jobID string) ([]*structs.Allocation, error) {
+	var out []*structs.Allocation
+	for _, a := range s.allocs {
+		if a.JobID == jobID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemState) AllocsByNode(nodeID string) ([]*structs.Allocation, error) {
+	var out []*structs.Allocation
+	for _, a := range s.allocs {
+		if a.NodeID == nodeID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemState) SchedulerConfig() (*structs.SchedulerConfiguration, error) {
+	return nil, nil
+}
+
+// Harness wires a MemState to a scheduler under test and records every
+// plan and follow-up evaluation submitted to it, standing in for the
+// server's state store and plan queue.
+type Harness struct {
+	t     *testing.T
+	index uint64
+
+	State *MemState
+
+	Plans []*structs.Plan
+	Evals []*structs.Evaluation
+}
+
+// NewHarness creates a Harness backed by an empty MemState.
+func NewHarness(t *testing.T) *Harness {
+	return &Harness{
+		t:     t,
+		State: newMemState(),
+	}
+}
+
+// NextIndex returns a monotonically increasing index, standing in for the
+// Raft index a real Upsert would be assigned.
+func (h *Harness) NextIndex() uint64 {
+	h.index++
+	return h.index
+}
+
+// SubmitPlan applies plan to the harness's state and records it. There's
+// only ever one "scheduling node" in these tests, so a plan is always
+// fully committed and never needs a state refresh.
+func (h *Harness) SubmitPlan(plan *structs.Plan) (*structs.PlanResult, State, error) {
+	h.Plans = append(h.Plans, plan)
+
+	for _, ids := range plan.NodeEvict {
+		for _, id := range ids {
+			delete(h.State.allocs, id)
+		}
+	}
+	for _, allocs := range plan.NodePreempt {
+		for _, a := range allocs {
+			delete(h.State.allocs, a.ID)
+		}
+	}
+	for _, allocs := range plan.NodeAllocation {
+		for _, a := range allocs {
+			h.State.allocs[a.ID] = a
+		}
+	}
+	for _, allocs := range plan.NodeUpdate {
+		for _, a := range allocs {
+			h.State.allocs[a.ID] = a
+		}
+	}
+
+	result := &structs.PlanResult{
+		NodeAllocation: plan.NodeAllocation,
+		NodeUpdate:     plan.NodeUpdate,
+	}
+	return result, nil, nil
+}
+
+// CreateEval records a follow-up evaluation queued by the scheduler, such
+// as the next batch of a rolling update.
+func (h *Harness) CreateEval(eval *structs.Evaluation) (string, error) {
+	h.Evals = append(h.Evals, eval)
+	return eval.ID, nil
+}
+
+// Process instantiates the scheduler produced by factory and runs it
+// against eval, using the harness's state and the harness itself as the
+// planner.
+func (h *Harness) Process(factory Factory, eval *structs.Evaluation) error {
+	logger := log.New(testLogWriter{h.t}, "", 0)
+	sched := factory(logger, h.State, h)
+	return sched.Process(eval)
+}
+
+// testLogWriter routes the scheduler's logger output through t.Logf so it
+// only shows up for failing tests.
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Logf("%s", p)
+	return len(p), nil
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"math/rand"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Iterator is implemented by each stage of the scheduler's node selection
+// pipeline. Next returns the next candidate (a *structs.Node for the
+// feasibility half of the stack, a *RankedNode for the ranking half), or
+// nil once the stage is exhausted. Reset rewinds the stage so it can be
+// reused for the next task group in the same evaluation.
+type Iterator interface {
+	Next() interface{}
+	Reset()
+}
+
+// StaticIterator yields a fixed set of nodes in order. It's mostly useful
+// as a deterministic source in tests.
+type StaticIterator struct {
+	ctx   *EvalContext
+	nodes []*structs.Node
+	idx   int
+}
+
+// NewStaticIterator creates a StaticIterator over nodes.
+func NewStaticIterator(ctx *EvalContext, nodes []*structs.Node) *StaticIterator {
+	return &StaticIterator{ctx: ctx, nodes: nodes}
+}
+
+func (it *StaticIterator) Next() interface{} {
+	if it.idx >= len(it.nodes) {
+		return nil
+	}
+	n := it.nodes[it.idx]
+	it.idx++
+	return n
+}
+
+func (it *StaticIterator) Reset() { it.idx = 0 }
+
+// RandomIterator visits a fixed set of nodes in a random order, used as
+// the source of the iterator stack to reduce collisions between
+// schedulers and to do basic load balancing across eligible nodes.
+type RandomIterator struct {
+	ctx   *EvalContext
+	nodes []*structs.Node
+	idx   int
+}
+
+// NewRandomIterator creates a RandomIterator over a shuffled copy of nodes.
+func NewRandomIterator(ctx *EvalContext, nodes []*structs.Node) *RandomIterator {
+	shuffled := make([]*structs.Node, len(nodes))
+	copy(shuffled, nodes)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return &RandomIterator{ctx: ctx, nodes: shuffled}
+}
+
+func (it *RandomIterator) Next() interface{} {
+	if it.idx >= len(it.nodes) {
+		return nil
+	}
+	n := it.nodes[it.idx]
+	it.idx++
+	return n
+}
+
+func (it *RandomIterator) Reset() { it.idx = 0 }
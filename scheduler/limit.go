@@ -0,0 +1,34 @@
+package scheduler
+
+// LimitIterator caps the number of ranked candidates pulled from source,
+// so the stack doesn't have to score every feasible node in the cluster -
+// visiting "enough" of them is sufficient for a good placement.
+type LimitIterator struct {
+	ctx    *EvalContext
+	source Iterator
+	limit  int
+	seen   int
+}
+
+// NewLimitIterator creates a LimitIterator that yields at most limit
+// candidates from source per Reset.
+func NewLimitIterator(ctx *EvalContext, source Iterator, limit int) *LimitIterator {
+	return &LimitIterator{ctx: ctx, source: source, limit: limit}
+}
+
+func (it *LimitIterator) Next() interface{} {
+	if it.seen >= it.limit {
+		return nil
+	}
+	raw := it.source.Next()
+	if raw == nil {
+		return nil
+	}
+	it.seen++
+	return raw
+}
+
+func (it *LimitIterator) Reset() {
+	it.seen = 0
+	it.source.Reset()
+}
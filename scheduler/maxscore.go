@@ -0,0 +1,38 @@
+package scheduler
+
+// MaxScoreIterator drains the ranked candidates produced by source and
+// returns the one with the highest score, recording every candidate's
+// score into the evaluation's metrics along the way.
+type MaxScoreIterator struct {
+	ctx    *EvalContext
+	source Iterator
+}
+
+// NewMaxScoreIterator creates a MaxScoreIterator over source.
+func NewMaxScoreIterator(ctx *EvalContext, source Iterator) *MaxScoreIterator {
+	return &MaxScoreIterator{ctx: ctx, source: source}
+}
+
+// Next scores every remaining candidate from source and returns the best
+// one, or nil if source produced no feasible candidates.
+func (it *MaxScoreIterator) Next() *RankedNode {
+	var best *RankedNode
+	for {
+		raw := it.source.Next()
+		if raw == nil {
+			break
+		}
+		ranked := raw.(*RankedNode)
+		it.ctx.Metrics().ScoreNode(ranked.Node, ranked.Score)
+
+		if best == nil || ranked.Score > best.Score {
+			best = ranked
+		}
+	}
+	return best
+}
+
+// Reset rewinds the stack so it can be reused for the next task group.
+func (it *MaxScoreIterator) Reset() {
+	it.source.Reset()
+}
@@ -0,0 +1,37 @@
+package scheduler
+
+import "github.com/hashicorp/nomad/nomad/structs"
+
+// RankedNode pairs a feasible node with the score and (if any) preemption
+// decision the ranking half of the iterator stack computed for it.
+type RankedNode struct {
+	Node  *structs.Node
+	Score float64
+
+	// PreemptedAllocs are the lower priority allocations that must be
+	// evicted from Node to make room for the pending placement.
+	PreemptedAllocs []*structs.Allocation
+}
+
+// FeasibleRankIterator bridges the feasibility-checking half of the
+// iterator stack to the ranking half, wrapping each feasible node in a
+// RankedNode for the later stages to fill in.
+type FeasibleRankIterator struct {
+	ctx    *EvalContext
+	source Iterator
+}
+
+// NewFeasibleRankIterator creates a FeasibleRankIterator over source.
+func NewFeasibleRankIterator(ctx *EvalContext, source Iterator) *FeasibleRankIterator {
+	return &FeasibleRankIterator{ctx: ctx, source: source}
+}
+
+func (it *FeasibleRankIterator) Next() interface{} {
+	raw := it.source.Next()
+	if raw == nil {
+		return nil
+	}
+	return &RankedNode{Node: raw.(*structs.Node)}
+}
+
+func (it *FeasibleRankIterator) Reset() { it.source.Reset() }
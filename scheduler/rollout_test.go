@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestNextRolloutBatch_StaggerAppliesAsDelay(t *testing.T) {
+	tg := &structs.TaskGroup{
+		Name: "web",
+		Update: &structs.UpdateStrategy{
+			MaxParallel: 3,
+			Stagger:     30 * time.Second,
+		},
+	}
+	groups := map[string]*structs.TaskGroup{
+		"web.0": tg, "web.1": tg, "web.2": tg,
+	}
+	indexed := map[string]*structs.Allocation{
+		"web.0": {Name: "web.0", TaskGroup: "web"},
+		"web.1": {Name: "web.1", TaskGroup: "web"},
+		"web.2": {Name: "web.2", TaskGroup: "web"},
+	}
+	destructive := []allocNameID{{Name: "web.0"}, {Name: "web.1"}, {Name: "web.2"}}
+
+	batch, rollingOut, wait := nextRolloutBatch(groups, destructive, nil, indexed)
+
+	// A configured Stagger limits a batch to one allocation regardless of
+	// MaxParallel, and since two are left over, the follow-up eval must
+	// be told to wait out the Stagger before continuing.
+	if len(batch) != 1 {
+		t.Fatalf("expected a staggered batch of 1, got %d", len(batch))
+	}
+	if !rollingOut {
+		t.Fatalf("expected more allocations left to roll out")
+	}
+	if wait != 30*time.Second {
+		t.Fatalf("expected the follow-up eval to wait out the Stagger, got %s", wait)
+	}
+}
+
+func TestNextRolloutBatch_NoStagger_NoWait(t *testing.T) {
+	tg := &structs.TaskGroup{
+		Name:   "web",
+		Update: &structs.UpdateStrategy{MaxParallel: 1},
+	}
+	groups := map[string]*structs.TaskGroup{"web.0": tg, "web.1": tg}
+	indexed := map[string]*structs.Allocation{
+		"web.0": {Name: "web.0", TaskGroup: "web"},
+		"web.1": {Name: "web.1", TaskGroup: "web"},
+	}
+	destructive := []allocNameID{{Name: "web.0"}, {Name: "web.1"}}
+
+	batch, rollingOut, wait := nextRolloutBatch(groups, destructive, nil, indexed)
+	if len(batch) != 1 || !rollingOut {
+		t.Fatalf("expected a batch of 1 with more to roll out, got %d batch, rollingOut=%v", len(batch), rollingOut)
+	}
+	if wait != 0 {
+		t.Fatalf("expected no wait without a configured Stagger, got %s", wait)
+	}
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"log"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Scheduler is the interface implemented by each scheduling algorithm
+// (service, batch, system, ...), dispatched by evaluation trigger reason.
+type Scheduler interface {
+	Process(eval *structs.Evaluation) error
+}
+
+// Factory instantiates a new Scheduler for a single evaluation.
+type Factory func(logger *log.Logger, state State, planner Planner) Scheduler
+
+// StateIterator is returned by State lookups that can produce more than one
+// result.
+type StateIterator interface {
+	Next() interface{}
+}
+
+// State is the read-only view of cluster state a scheduler needs in order
+// to make placement decisions.
+type State interface {
+	GetJobByID(jobID string) (*structs.Job, error)
+	GetNodeByID(nodeID string) (*structs.Node, error)
+	NodesByDatacenterStatus(datacenter, status string) (StateIterator, error)
+	AllocsByJob(jobID string) ([]*structs.Allocation, error)
+	AllocsByNode(nodeID string) ([]*structs.Allocation, error)
+	SchedulerConfig() (*structs.SchedulerConfiguration, error)
+}
+
+// Planner is used to submit the plan produced by a scheduler and to queue
+// follow-up evaluations, such as the next batch of a rolling update.
+type Planner interface {
+	SubmitPlan(plan *structs.Plan) (*structs.PlanResult, State, error)
+	CreateEval(eval *structs.Evaluation) (string, error)
+}
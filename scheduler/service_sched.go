@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -13,6 +14,18 @@ const (
 	// maxScheduleAttempts is used to limit the number of times
 	// we will attempt to schedule if we continue to hit conflicts.
 	maxScheduleAttempts = 5
+
+	// defaultMaxParallel is the number of destructive updates a task group
+	// is allowed to have in flight at once when it does not specify its
+	// own UpdateStrategy.
+	defaultMaxParallel = 1
+
+	// defaultMinPreemptionPriorityGap is the minimum difference in
+	// priority required before a placement is allowed to preempt an
+	// existing allocation, used when the cluster has not configured its
+	// own value via SchedulerConfiguration. This keeps preemption from
+	// thrashing between jobs of nearly equal priority.
+	defaultMinPreemptionPriorityGap = 10
 )
 
 // ServiceScheduler is used for 'service' type jobs. This scheduler is
@@ -111,10 +124,31 @@ START:
 	// Add all the evicts
 	addEvictsToPlan(plan, evict, indexed)
 
-	// For simplicity, we treat all updates as an evict + place.
-	// XXX: This should be done with rolling in-place updates instead.
-	addEvictsToPlan(plan, update, indexed)
-	place = append(place, update...)
+	// Split the updates into those that can be applied to the existing
+	// allocation in place (only mutable fields such as the image, env or
+	// resource counts changed) and those that require a destructive
+	// evict + place because the task group's constraints, drivers or
+	// resource footprint no longer fit on the node the alloc is on.
+	inplace, destructive := s.diffUpdateKind(groups, update, indexed)
+	addUpdatesToPlan(plan, job, inplace, indexed, groups)
+
+	// Destructive updates are rolled out in bounded batches so a bad
+	// update doesn't take down every instance of the task group at once.
+	// Allocations that don't fit in this evaluation's batch are left
+	// alone; a follow-up evaluation continues the rollout once the
+	// current batch reports healthy.
+	batch, rollingOut, wait := nextRolloutBatch(groups, destructive, ignore, indexed)
+	addEvictsToPlan(plan, batch, indexed)
+	place = append(place, batch...)
+
+	if rollingOut {
+		follow := eval.NextRollingEval()
+		follow.Wait = wait
+		if _, err := s.planner.CreateEval(follow); err != nil {
+			return fmt.Errorf("failed to enqueue follow-up eval for job '%s': %v",
+				job.ID, err)
+		}
+	}
 
 	// Get the iteration stack
 	stack, err := s.iterStack(job, plan)
@@ -151,14 +185,241 @@ START:
 	return nil
 }
 
+// diffUpdateKind splits allocations slated for update into two sets: those
+// whose task group only changed in mutable fields (image tag, env, resource
+// counts that still fit on the node the allocation already occupies) and can
+// be updated in place, and those whose change is destructive and must go
+// through an evict + place cycle.
+func (s *ServiceScheduler) diffUpdateKind(groups map[string]*structs.TaskGroup, update []allocNameID,
+	indexed map[string]*structs.Allocation) (inplace, destructive []allocNameID) {
+	for _, u := range update {
+		taskGroup := groups[u.Name]
+		existing := indexed[u.Name]
+		if existing != nil && s.allocFitsInPlace(existing, taskGroup) {
+			inplace = append(inplace, u)
+		} else {
+			destructive = append(destructive, u)
+		}
+	}
+	return inplace, destructive
+}
+
+// allocFitsInPlace reports whether an existing allocation can absorb the
+// task group's new definition without being rescheduled: the drivers and
+// constraints must be unchanged, and the new resource footprint must still
+// fit on the node the allocation is already running on.
+func (s *ServiceScheduler) allocFitsInPlace(alloc *structs.Allocation, updated *structs.TaskGroup) bool {
+	if alloc.Job == nil {
+		return false
+	}
+	existing := alloc.Job.LookupTaskGroup(updated.Name)
+	if existing == nil || len(existing.Tasks) != len(updated.Tasks) {
+		return false
+	}
+	if !constraintsEqual(existing.Constraints, updated.Constraints) {
+		return false
+	}
+	for i, task := range existing.Tasks {
+		newTask := updated.Tasks[i]
+		if task.Driver != newTask.Driver {
+			return false
+		}
+		if !constraintsEqual(task.Constraints, newTask.Constraints) {
+			return false
+		}
+	}
+	return s.resourceDeltaFits(alloc, updated)
+}
+
+// resourceDeltaFits reports whether the node the allocation is already
+// running on has enough unallocated capacity to absorb the difference
+// between the task group's current resource footprint and its updated one.
+// Updates that only shrink the footprint always fit.
+func (s *ServiceScheduler) resourceDeltaFits(alloc *structs.Allocation, updated *structs.TaskGroup) bool {
+	node, err := s.state.GetNodeByID(alloc.NodeID)
+	if err != nil || node == nil {
+		return false
+	}
+
+	newSize := new(structs.Resources)
+	for _, task := range updated.Tasks {
+		newSize.Add(task.Resources)
+	}
+
+	delta := new(structs.Resources)
+	delta.Add(newSize)
+	delta.Subtract(alloc.Resources)
+	if delta.CPU <= 0 && delta.MemoryMB <= 0 && delta.DiskMB <= 0 {
+		return true
+	}
+
+	free, err := s.freeNodeResources(node)
+	if err != nil {
+		return false
+	}
+	return delta.CPU <= free.CPU && delta.MemoryMB <= free.MemoryMB && delta.DiskMB <= free.DiskMB
+}
+
+// freeNodeResources returns a node's unallocated resources by subtracting
+// the footprint of every allocation currently placed on it from its total
+// capacity.
+func (s *ServiceScheduler) freeNodeResources(node *structs.Node) (*structs.Resources, error) {
+	free := new(structs.Resources)
+	free.Add(node.Resources)
+
+	allocs, err := s.state.AllocsByNode(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range allocs {
+		free.Subtract(a.Resources)
+	}
+	return free, nil
+}
+
+// constraintsEqual does a simple order-sensitive comparison of two
+// constraint lists. A reordering of equivalent constraints is treated as a
+// destructive change; this keeps the in-place fast path conservative.
+func constraintsEqual(a, b []*structs.Constraint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, c := range a {
+		if *c != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addUpdatesToPlan appends in-place updates to the plan. Unlike an evict,
+// the allocation keeps its ID and NodeID, but its Job and Resources are
+// advanced to the new task group definition so the client has something to
+// reconcile the running tasks against.
+func addUpdatesToPlan(plan *structs.Plan, job *structs.Job, update []allocNameID,
+	indexed map[string]*structs.Allocation, groups map[string]*structs.TaskGroup) {
+	for _, u := range update {
+		existing := indexed[u.Name]
+		taskGroup := groups[u.Name]
+		if existing == nil || taskGroup == nil {
+			continue
+		}
+
+		size := new(structs.Resources)
+		for _, task := range taskGroup.Tasks {
+			size.Add(task.Resources)
+		}
+
+		updated := new(structs.Allocation)
+		*updated = *existing
+		updated.Job = job
+		updated.Resources = size
+		plan.AppendUpdate(updated)
+	}
+}
+
+// nextRolloutBatch returns the subset of destructive updates that this
+// evaluation is allowed to act on, honoring each task group's
+// UpdateStrategy.MaxParallel/Stagger and the health of any batch already in
+// flight. settled holds the allocations diffAllocs decided don't need any
+// change, which is where a previously rolled-out batch shows up once it
+// stops needing updates. It reports whether a follow-up evaluation is
+// needed to continue rolling out the remainder, and if so how long the
+// planner should wait before dispatching it so that Stagger is honored as
+// an actual delay between batches rather than just a maxParallel=1 clamp.
+func nextRolloutBatch(groups map[string]*structs.TaskGroup, destructive, settled []allocNameID,
+	indexed map[string]*structs.Allocation) ([]allocNameID, bool, time.Duration) {
+	if len(destructive) == 0 {
+		return nil, false, 0
+	}
+
+	byGroup := make(map[string][]allocNameID)
+	for _, u := range destructive {
+		existing := indexed[u.Name]
+		if existing == nil {
+			continue
+		}
+		byGroup[existing.TaskGroup] = append(byGroup[existing.TaskGroup], u)
+	}
+
+	var batch []allocNameID
+	var remaining bool
+	var wait time.Duration
+	for groupName, updates := range byGroup {
+		strategy := groups[updates[0].Name].Update
+		maxParallel := defaultMaxParallel
+		if strategy != nil && strategy.MaxParallel > 0 {
+			maxParallel = strategy.MaxParallel
+		}
+
+		if !previousBatchHealthy(groupName, settled, indexed, strategy) {
+			// The last batch for this group hasn't cleared its
+			// MinHealthyTime yet; don't start a new one.
+			remaining = true
+			continue
+		}
+
+		if strategy != nil && strategy.Stagger > 0 && maxParallel > 1 {
+			// A configured Stagger means members of a batch aren't
+			// launched together; start one per evaluation and let the
+			// health gate above pace the rest across follow-up evals.
+			maxParallel = 1
+		}
+
+		if len(updates) > maxParallel {
+			batch = append(batch, updates[:maxParallel]...)
+			remaining = true
+			if strategy != nil && strategy.Stagger > wait {
+				wait = strategy.Stagger
+			}
+		} else {
+			batch = append(batch, updates...)
+		}
+	}
+	return batch, remaining, wait
+}
+
+// previousBatchHealthy reports whether the allocations of groupName that
+// are already on the new task group definition (i.e. settled, no longer
+// needing an update) have been healthy for at least MinHealthyTime, gating
+// the next batch of a rolling update. Task groups without an
+// UpdateStrategy, or that have no settled allocations yet, are treated as
+// healthy so the first batch is never blocked.
+func previousBatchHealthy(groupName string, settled []allocNameID, indexed map[string]*structs.Allocation,
+	strategy *structs.UpdateStrategy) bool {
+	if strategy == nil || strategy.MinHealthyTime == 0 {
+		return true
+	}
+	for _, s := range settled {
+		alloc := indexed[s.Name]
+		if alloc == nil || alloc.TaskGroup != groupName {
+			continue
+		}
+		if alloc.Status != structs.AllocStatusRunning {
+			return false
+		}
+		if time.Since(alloc.ModifyTime) < strategy.MinHealthyTime {
+			return false
+		}
+	}
+	return true
+}
+
 // IteratorStack is used to hold pointers to each of the
 // iterators which are chained together to do selection.
 // Half of the stack is used for feasibility checking, while
 // the second half of the stack is used for ranking and selection.
 type IteratorStack struct {
-	Context             *EvalContext
-	BaseNodes           []*structs.Node
-	Source              *StaticIterator
+	Context   *EvalContext
+	BaseNodes []*structs.Node
+
+	// NodesAvailable is the count of base nodes considered for placement,
+	// keyed by datacenter. It's re-applied to the context's metrics after
+	// every Reset, since Reset clears it along with the per-attempt
+	// filtering counts.
+	NodesAvailable map[string]int
+
+	Source              *RandomIterator
 	JobConstraint       *ConstraintIterator
 	TaskGroupDrivers    *DriverIterator
 	TaskGroupConstraint *ConstraintIterator
@@ -184,6 +445,7 @@ func (s *ServiceScheduler) iterStack(job *structs.Job,
 		return nil, err
 	}
 	stack.BaseNodes = nodes
+	stack.NodesAvailable = nodesAvailableByDC(nodes)
 
 	// Create the source iterator. We randomize the order we visit nodes
 	// to reduce collisions between schedulers and to do a basic load
@@ -201,9 +463,12 @@ func (s *ServiceScheduler) iterStack(job *structs.Job,
 	stack.RankSource = NewFeasibleRankIterator(stack.Context, stack.TaskGroupConstraint)
 
 	// Apply the bin packing, this depends on the resources needed by
-	// a particular task group.
-	// TODO: Support eviction in the future
-	stack.BinPack = NewBinPackIterator(stack.Context, stack.RankSource, nil, false, job.Priority)
+	// a particular task group. Eviction is enabled so that a sufficiently
+	// higher priority job can preempt lower priority allocations that
+	// would otherwise make a node infeasible; the priority gap keeps jobs
+	// of near-equal priority from preempting one another, and is an
+	// operator-tunable cluster setting rather than a fixed constant.
+	stack.BinPack = NewBinPackIterator(stack.Context, stack.RankSource, nil, true, job.Priority, s.minPreemptionPriorityGap())
 
 	// Apply a limit function. This is to avoid scanning *every* possible node.
 	// Instead we need to visit "enough". Using a log of the total number of
@@ -223,6 +488,29 @@ func (s *ServiceScheduler) iterStack(job *structs.Job,
 	return stack, nil
 }
 
+// minPreemptionPriorityGap returns the operator-configured minimum priority
+// gap required for preemption, read from the cluster's
+// SchedulerConfiguration. It falls back to defaultMinPreemptionPriorityGap
+// when the cluster hasn't set one, so operators can tune preemption churn
+// without a code change.
+func (s *ServiceScheduler) minPreemptionPriorityGap() int {
+	config, err := s.state.SchedulerConfig()
+	if err != nil || config == nil || config.MinPreemptionPriorityGap <= 0 {
+		return defaultMinPreemptionPriorityGap
+	}
+	return config.MinPreemptionPriorityGap
+}
+
+// nodesAvailableByDC counts how many base nodes were gathered per
+// datacenter, for the NodesAvailable placement-diagnostics metric.
+func nodesAvailableByDC(nodes []*structs.Node) map[string]int {
+	counts := make(map[string]int)
+	for _, n := range nodes {
+		counts[n.Datacenter]++
+	}
+	return counts
+}
+
 // baseNodes returns all the ready nodes in a datacenter that this
 // job has specified is usable.
 func (s *ServiceScheduler) baseNodes(job *structs.Job) ([]*structs.Node, error) {
@@ -243,6 +531,42 @@ func (s *ServiceScheduler) baseNodes(job *structs.Job) ([]*structs.Node, error)
 	return out, nil
 }
 
+// copyAllocMetric returns a deep copy of an AllocMetric so it can be
+// stashed on an allocation or a blocked-placement report without aliasing
+// the maps that the shared EvalContext metrics get reset on
+// between placement attempts.
+func copyAllocMetric(m *structs.AllocMetric) *structs.AllocMetric {
+	out := new(structs.AllocMetric)
+	*out = *m
+
+	out.ClassFiltered = make(map[string]int, len(m.ClassFiltered))
+	for k, v := range m.ClassFiltered {
+		out.ClassFiltered[k] = v
+	}
+	out.ConstraintFiltered = make(map[string]int, len(m.ConstraintFiltered))
+	for k, v := range m.ConstraintFiltered {
+		out.ConstraintFiltered[k] = v
+	}
+	out.ClassExhausted = make(map[string]int, len(m.ClassExhausted))
+	for k, v := range m.ClassExhausted {
+		out.ClassExhausted[k] = v
+	}
+	out.DimensionExhausted = make(map[string]int, len(m.DimensionExhausted))
+	for k, v := range m.DimensionExhausted {
+		out.DimensionExhausted[k] = v
+	}
+	out.Scores = make(map[string]float64, len(m.Scores))
+	for k, v := range m.Scores {
+		out.Scores[k] = v
+	}
+	out.NodesAvailable = make(map[string]int, len(m.NodesAvailable))
+	for k, v := range m.NodesAvailable {
+		out.NodesAvailable[k] = v
+	}
+
+	return out
+}
+
 func (s *ServiceScheduler) planAllocations(stack *IteratorStack, job *structs.Job, plan *structs.Plan,
 	place []allocNameID, groups map[string]*structs.TaskGroup) error {
 
@@ -268,14 +592,40 @@ func (s *ServiceScheduler) planAllocations(stack *IteratorStack, job *structs.Jo
 		stack.TaskGroupConstraint.SetConstraints(constr)
 		stack.BinPack.SetResources(size)
 
-		// Select the best fit
+		// Reset the shared metrics before each attempt so the counts
+		// recorded by the iterators (nodes filtered for a missing
+		// driver, a failed constraint, insufficient resources, ...)
+		// reflect only this task group's placement.
+		start := time.Now()
+		stack.Context.Metrics().Reset()
+		stack.Context.Metrics().SetNodesAvailable(stack.NodesAvailable)
+
+		// Select the best fit. The context's metrics are reused across
+		// iterations and reset on the next one, so deep-copy the maps
+		// before snapshotting this attempt's result.
 		option := stack.MaxScore.Next()
+		metric := copyAllocMetric(stack.Context.Metrics())
+		metric.AllocationTime = time.Since(start)
+
 		if option == nil {
-			s.logger.Printf("[DEBUG] sched: failed to place alloc %s for job %s",
-				missing, job.ID)
+			s.logger.Printf("[DEBUG] sched: failed to place alloc %s for job %s, evaluated %d nodes, filtered %d",
+				missing, job.ID, metric.NodesEvaluated, metric.NodesFiltered)
+			plan.AppendBlocked(missing.Name, metric)
 			continue
 		}
 
+		// If the placement required preempting lower priority
+		// allocations to free up room, evict them as part of the same
+		// plan so the preemption and the new placement commit
+		// atomically.
+		if len(option.PreemptedAllocs) > 0 {
+			s.logger.Printf("[DEBUG] sched: alloc %s for job %s preempts %d lower priority allocs on node %s",
+				missing, job.ID, len(option.PreemptedAllocs), option.Node.ID)
+			for _, preempted := range option.PreemptedAllocs {
+				plan.AppendPreemption(preempted)
+			}
+		}
+
 		// Create an allocation for this
 		alloc := &structs.Allocation{
 			ID:        mock.GenerateUUID(),
@@ -283,8 +633,9 @@ func (s *ServiceScheduler) planAllocations(stack *IteratorStack, job *structs.Jo
 			NodeID:    option.Node.ID,
 			JobID:     job.ID,
 			Job:       job,
+			TaskGroup: taskGroup.Name,
 			Resources: size,
-			Metrics:   nil,
+			Metrics:   metric,
 			Status:    structs.AllocStatusPending,
 		}
 		plan.AppendAlloc(alloc)
@@ -295,7 +646,125 @@ func (s *ServiceScheduler) planAllocations(stack *IteratorStack, job *structs.Jo
 // handleNodeUpdate is used to handle an update to a node status where
 // there is an existing allocation for this job
 func (s *ServiceScheduler) handleNodeUpdate(eval *structs.Evaluation) error {
-	// TODO
+	attempts := 0
+START:
+	// Check the attempt count
+	if attempts == maxScheduleAttempts {
+		return fmt.Errorf("maximum schedule attempts reached (%d)", attempts)
+	}
+	attempts += 1
+
+	// Lookup the node that triggered this evaluation
+	node, err := s.state.GetNodeByID(eval.NodeID)
+	if err != nil {
+		return fmt.Errorf("failed to get node '%s': %v", eval.NodeID, err)
+	}
+	if node == nil {
+		s.logger.Printf("[DEBUG] sched: skipping eval %s, node %s not found",
+			eval.ID, eval.NodeID)
+		return nil
+	}
+
+	// A node that is both ready and not draining doesn't require any
+	// action; the allocations already on it are still valid. A drain
+	// typically leaves the node's Status as Ready with Drain set, so both
+	// must be checked or a drain-triggered eval would no-op.
+	if node.Status == structs.NodeStatusReady && !node.Drain {
+		return nil
+	}
+
+	// Lookup the Job by ID
+	job, err := s.state.GetJobByID(eval.JobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job '%s': %v",
+			eval.JobID, err)
+	}
+	if job == nil {
+		s.logger.Printf("[DEBUG] sched: skipping eval %s, job %s not found",
+			eval.ID, eval.JobID)
+		return nil
+	}
+
+	groups := materializeTaskGroups(job)
+	if len(groups) == 0 {
+		return nil
+	}
+
+	// Lookup the allocations by JobID and narrow down to the ones that
+	// actually live on the down/draining node; the rest of the job is
+	// unaffected.
+	allocs, err := s.state.AllocsByJob(eval.JobID)
+	if err != nil {
+		return fmt.Errorf("failed to get allocs for job '%s': %v",
+			eval.JobID, err)
+	}
+
+	var affected []*structs.Allocation
+	for _, alloc := range allocs {
+		if alloc.NodeID == eval.NodeID {
+			affected = append(affected, alloc)
+		}
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+	s.logger.Printf("[DEBUG] sched: eval %s job %s needs %d allocs rescheduled off node %s",
+		eval.ID, eval.JobID, len(affected), eval.NodeID)
+
+	// Start a plan for this evaluation, evicting the affected allocations
+	// and feeding them back through the iterator stack so they land on
+	// another eligible node.
+	plan := eval.MakePlan(job)
+	place := make([]allocNameID, 0, len(affected))
+	for _, alloc := range affected {
+		plan.AppendEvict(alloc)
+		place = append(place, allocNameID{Name: alloc.Name})
+	}
+
+	stack, err := s.iterStack(job, plan)
+	if err != nil {
+		return fmt.Errorf("failed to create iter stack: %v", err)
+	}
+
+	// A draining node is still Ready and so is among the base nodes; drop
+	// it from the candidates so the replacements land elsewhere.
+	if node.Drain {
+		kept := make([]*structs.Node, 0, len(stack.Source.nodes))
+		for _, n := range stack.Source.nodes {
+			if n.ID != node.ID {
+				kept = append(kept, n)
+			}
+		}
+		stack.Source.nodes = kept
+		stack.BaseNodes = kept
+		stack.NodesAvailable = nodesAvailableByDC(kept)
+	}
+
+	if err := s.planAllocations(stack, job, plan, place, groups); err != nil {
+		return fmt.Errorf("failed to plan allocations: %v", err)
+	}
+
+	// Submit the plan
+	planResult, newState, err := s.planner.SubmitPlan(plan)
+	if err != nil {
+		return err
+	}
+
+	// If we got a state refresh, try again to ensure we
+	// are not missing any allocations
+	if newState != nil {
+		s.state = newState
+		stack.Context.SetState(newState)
+		goto START
+	}
+
+	// Try again if the plan was not fully committed
+	fullCommit, expected, actual := planResult.FullCommit(plan)
+	if !fullCommit {
+		s.logger.Printf("[DEBUG] sched: eval %s job %s attempted %d placements, %d placed",
+			eval.ID, eval.JobID, expected, actual)
+		goto START
+	}
 	return nil
 }
 
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestServiceSched_NodeDown_PartialEvict(t *testing.T) {
+	h := NewHarness(t)
+
+	// Two nodes: one goes down, one stays up.
+	down := mock.Node()
+	down.Status = structs.NodeStatusDown
+	up := mock.Node()
+	noErr(t, h.State.UpsertNode(h.NextIndex(), down))
+	noErr(t, h.State.UpsertNode(h.NextIndex(), up))
+
+	job := mock.Job()
+	job.TaskGroups[0].Count = 3
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Only one of the three allocs lives on the node that went down.
+	allocs := make([]*structs.Allocation, 3)
+	for i := range allocs {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.TaskGroup = job.TaskGroups[0].Name
+		if i == 0 {
+			alloc.NodeID = down.ID
+		} else {
+			alloc.NodeID = up.ID
+		}
+		allocs[i] = alloc
+	}
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), allocs))
+
+	eval := &structs.Evaluation{
+		ID:          mock.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+		NodeID:      down.ID,
+	}
+	noErr(t, h.Process(NewServiceScheduler, eval))
+
+	if len(h.Plans) != 1 {
+		t.Fatalf("expected one plan, got %d", len(h.Plans))
+	}
+	plan := h.Plans[0]
+
+	// Only the alloc on the down node should be evicted.
+	evicted := 0
+	for _, nodeAllocs := range plan.NodeEvict {
+		evicted += len(nodeAllocs)
+	}
+	if evicted != 1 {
+		t.Fatalf("expected 1 eviction, got %d", evicted)
+	}
+	if _, ok := plan.NodeEvict[down.ID]; !ok {
+		t.Fatalf("expected eviction on down node %s", down.ID)
+	}
+	if _, ok := plan.NodeEvict[up.ID]; ok {
+		t.Fatalf("did not expect eviction on unaffected node %s", up.ID)
+	}
+
+	// The evicted alloc should be replaced with a single new placement.
+	placed := 0
+	for _, nodeAllocs := range plan.NodeAllocation {
+		placed += len(nodeAllocs)
+	}
+	if placed != 1 {
+		t.Fatalf("expected a single replacement placement, got %d", placed)
+	}
+}
+
+func TestServiceSched_NodeUpdate_NodeReady(t *testing.T) {
+	h := NewHarness(t)
+
+	node := mock.Node()
+	node.Status = structs.NodeStatusReady
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	job := mock.Job()
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.TaskGroup = job.TaskGroups[0].Name
+	alloc.NodeID = node.ID
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	eval := &structs.Evaluation{
+		ID:          mock.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+		NodeID:      node.ID,
+	}
+	noErr(t, h.Process(NewServiceScheduler, eval))
+
+	// A node returning to ready requires no scheduler action.
+	if len(h.Plans) != 0 {
+		t.Fatalf("expected no plan, got %d", len(h.Plans))
+	}
+}
+
+func TestServiceSched_NodeUpdate_Drain(t *testing.T) {
+	h := NewHarness(t)
+
+	// A draining node is still Ready; only its Drain flag is set.
+	draining := mock.Node()
+	draining.Status = structs.NodeStatusReady
+	draining.Drain = true
+	up := mock.Node()
+	noErr(t, h.State.UpsertNode(h.NextIndex(), draining))
+	noErr(t, h.State.UpsertNode(h.NextIndex(), up))
+
+	job := mock.Job()
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.TaskGroup = job.TaskGroups[0].Name
+	alloc.NodeID = draining.ID
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	eval := &structs.Evaluation{
+		ID:          mock.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerNodeUpdate,
+		JobID:       job.ID,
+		NodeID:      draining.ID,
+	}
+	noErr(t, h.Process(NewServiceScheduler, eval))
+
+	if len(h.Plans) != 1 {
+		t.Fatalf("expected one plan, got %d", len(h.Plans))
+	}
+	plan := h.Plans[0]
+
+	if _, ok := plan.NodeEvict[draining.ID]; !ok {
+		t.Fatalf("expected the alloc on the draining node to be evicted")
+	}
+	if len(plan.NodeAllocation) == 0 {
+		t.Fatalf("expected a replacement placement off the draining node")
+	}
+	if _, ok := plan.NodeAllocation[draining.ID]; ok {
+		t.Fatalf("did not expect the replacement on the draining node %s", draining.ID)
+	}
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestServiceScheduler_AllocFitsInPlace(t *testing.T) {
+	h := NewHarness(t)
+	s := &ServiceScheduler{state: h.State}
+
+	node := mock.Node()
+	node.Resources = &structs.Resources{CPU: 1000, MemoryMB: 1000, DiskMB: 1000}
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	job := mock.Job()
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.NodeID = node.ID
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	// A bump in resources that still fits on the node is in-place.
+	grown := job.TaskGroups[0]
+	grownCopy := *grown
+	grownCopy.Tasks = []*structs.Task{{
+		Name:      "web",
+		Driver:    "exec",
+		Resources: &structs.Resources{CPU: 600, MemoryMB: 300, DiskMB: 600},
+	}}
+	if !s.allocFitsInPlace(alloc, &grownCopy) {
+		t.Fatalf("expected a resource bump that still fits on the node to be in-place")
+	}
+
+	// A changed driver is always destructive.
+	driverChanged := grownCopy
+	driverChanged.Tasks = []*structs.Task{{
+		Name:      "web",
+		Driver:    "docker",
+		Resources: &structs.Resources{CPU: 500, MemoryMB: 256, DiskMB: 512},
+	}}
+	if s.allocFitsInPlace(alloc, &driverChanged) {
+		t.Fatalf("expected a driver change to be destructive")
+	}
+
+	// A resource bump that no longer fits on the node is destructive.
+	tooBig := grownCopy
+	tooBig.Tasks = []*structs.Task{{
+		Name:      "web",
+		Driver:    "exec",
+		Resources: &structs.Resources{CPU: 5000, MemoryMB: 300, DiskMB: 600},
+	}}
+	if s.allocFitsInPlace(alloc, &tooBig) {
+		t.Fatalf("expected a resource bump that doesn't fit on the node to be destructive")
+	}
+}
+
+func TestServiceScheduler_DiffUpdateKind(t *testing.T) {
+	h := NewHarness(t)
+	s := &ServiceScheduler{state: h.State}
+
+	node := mock.Node()
+	node.Resources = &structs.Resources{CPU: 1000, MemoryMB: 1000, DiskMB: 1000}
+	noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+
+	job := mock.Job()
+	inplaceAlloc := mock.Alloc()
+	inplaceAlloc.Name = "web.0"
+	inplaceAlloc.Job = job
+	inplaceAlloc.NodeID = node.ID
+
+	destructiveAlloc := mock.Alloc()
+	destructiveAlloc.Name = "web.1"
+	destructiveAlloc.Job = job
+	destructiveAlloc.NodeID = node.ID
+
+	indexed := map[string]*structs.Allocation{
+		"web.0": inplaceAlloc,
+		"web.1": destructiveAlloc,
+	}
+
+	unchanged := job.TaskGroups[0]
+	changedDriver := *unchanged
+	changedDriver.Tasks = []*structs.Task{{Name: "web", Driver: "docker", Resources: unchanged.Tasks[0].Resources}}
+
+	groups := map[string]*structs.TaskGroup{
+		"web.0": unchanged,
+		"web.1": &changedDriver,
+	}
+	update := []allocNameID{{Name: "web.0"}, {Name: "web.1"}}
+
+	inplace, destructive := s.diffUpdateKind(groups, update, indexed)
+	if len(inplace) != 1 || inplace[0].Name != "web.0" {
+		t.Fatalf("expected web.0 to be in-place, got %v", inplace)
+	}
+	if len(destructive) != 1 || destructive[0].Name != "web.1" {
+		t.Fatalf("expected web.1 to be destructive, got %v", destructive)
+	}
+}
+
+func TestAddUpdatesToPlan(t *testing.T) {
+	job := mock.Job()
+	existing := mock.Alloc()
+	existing.Name = "web.0"
+	existing.NodeID = "node-1"
+	indexed := map[string]*structs.Allocation{"web.0": existing}
+	groups := map[string]*structs.TaskGroup{"web.0": job.TaskGroups[0]}
+
+	plan := &structs.Plan{}
+	addUpdatesToPlan(plan, job, []allocNameID{{Name: "web.0"}}, indexed, groups)
+
+	allocs := plan.NodeUpdate["node-1"]
+	if len(allocs) != 1 {
+		t.Fatalf("expected one update on node-1, got %d", len(allocs))
+	}
+	updated := allocs[0]
+	if updated.ID != existing.ID || updated.NodeID != existing.NodeID {
+		t.Fatalf("expected the update to keep the existing alloc's ID and node")
+	}
+	if updated.Job != job {
+		t.Fatalf("expected the update to advance to the new job")
+	}
+	if updated.Resources.CPU != 500 {
+		t.Fatalf("expected the update to carry the task group's resource footprint, got %+v", updated.Resources)
+	}
+}
+
+func TestPreviousBatchHealthy(t *testing.T) {
+	strategy := &structs.UpdateStrategy{MinHealthyTime: time.Minute}
+
+	healthy := &structs.Allocation{Name: "web.0", TaskGroup: "web", Status: structs.AllocStatusRunning, ModifyTime: time.Now().Add(-2 * time.Minute)}
+	tooYoung := &structs.Allocation{Name: "web.1", TaskGroup: "web", Status: structs.AllocStatusRunning, ModifyTime: time.Now()}
+	indexed := map[string]*structs.Allocation{"web.0": healthy, "web.1": tooYoung}
+
+	if !previousBatchHealthy("web", []allocNameID{{Name: "web.0"}}, indexed, strategy) {
+		t.Fatalf("expected a long-running alloc to count as healthy")
+	}
+	if previousBatchHealthy("web", []allocNameID{{Name: "web.1"}}, indexed, strategy) {
+		t.Fatalf("expected a just-updated alloc to not yet be healthy")
+	}
+	if !previousBatchHealthy("web", nil, indexed, nil) {
+		t.Fatalf("expected no UpdateStrategy to never block a batch")
+	}
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// allocNameID identifies a single desired allocation instance, e.g.
+// "web.0". It doubles as the key into both a job's materialized task
+// groups and the index of existing allocations.
+type allocNameID struct {
+	Name string
+}
+
+// String lets an allocNameID be used directly in a %s format verb.
+func (a allocNameID) String() string {
+	return a.Name
+}
+
+// materializeTaskGroups expands a job's task groups into one entry per
+// desired allocation instance, keyed by the instance's name.
+func materializeTaskGroups(job *structs.Job) map[string]*structs.TaskGroup {
+	out := make(map[string]*structs.TaskGroup)
+	for _, tg := range job.TaskGroups {
+		for i := 0; i < tg.Count; i++ {
+			out[fmt.Sprintf("%s.%d", tg.Name, i)] = tg
+		}
+	}
+	return out
+}
+
+// indexAllocs builds a lookup of existing allocations by instance name.
+func indexAllocs(allocs []*structs.Allocation) map[string]*structs.Allocation {
+	out := make(map[string]*structs.Allocation, len(allocs))
+	for _, alloc := range allocs {
+		out[alloc.Name] = alloc
+	}
+	return out
+}
+
+// diffAllocs compares the set of allocations a job requires against the
+// allocations that already exist, splitting them into allocations to
+// place, allocations to update, allocations to evict (the task group they
+// belonged to was removed or scaled down), and allocations that already
+// match the job and need no action.
+func diffAllocs(job *structs.Job, groups map[string]*structs.TaskGroup,
+	indexed map[string]*structs.Allocation) (place, update, evict, ignore []allocNameID) {
+	for name := range groups {
+		existing, ok := indexed[name]
+		if !ok {
+			place = append(place, allocNameID{Name: name})
+			continue
+		}
+		if existing.Job == nil || existing.Job.ModifyIndex != job.ModifyIndex {
+			update = append(update, allocNameID{Name: name})
+		} else {
+			ignore = append(ignore, allocNameID{Name: name})
+		}
+	}
+	for name := range indexed {
+		if _, ok := groups[name]; !ok {
+			evict = append(evict, allocNameID{Name: name})
+		}
+	}
+	return
+}
+
+// addEvictsToPlan appends an eviction to the plan for each named
+// allocation that still exists.
+func addEvictsToPlan(plan *structs.Plan, allocs []allocNameID, indexed map[string]*structs.Allocation) {
+	for _, a := range allocs {
+		if existing, ok := indexed[a.Name]; ok {
+			plan.AppendEvict(existing)
+		}
+	}
+}